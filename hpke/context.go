@@ -0,0 +1,75 @@
+package hpke
+
+import (
+	"crypto/cipher"
+	"errors"
+)
+
+// ErrExportOnly is returned by Seal and Open when called on a context whose
+// Suite.AeadID is AeadExportOnly: such a context can only be used to export
+// secrets.
+var ErrExportOnly = errors.New("hpke: context is export-only")
+
+// encdecCtx holds the state shared by a Sealer and an Opener, as produced by
+// the key schedule.
+type encdecCtx struct {
+	suite          Suite
+	aead           cipher.AEAD
+	baseNonce      []byte
+	sequenceNumber []byte
+	exporterSecret []byte
+}
+
+func (ctx *encdecCtx) Export(expCtx []byte, length uint16) []byte {
+	return labeledExpand(
+		ctx.suite.KdfID, ctx.suite.suiteID(), ctx.exporterSecret, "sec", expCtx, int(length),
+	)
+}
+
+// nextNonce computes base_nonce XOR sequence_number and increments
+// sequence_number, per RFC 9180 §5.2.
+func (ctx *encdecCtx) nextNonce() ([]byte, error) {
+	nonce := make([]byte, len(ctx.baseNonce))
+	for i := range nonce {
+		nonce[i] = ctx.baseNonce[i] ^ ctx.sequenceNumber[i]
+	}
+	for i := len(ctx.sequenceNumber) - 1; i >= 0; i-- {
+		ctx.sequenceNumber[i]++
+		if ctx.sequenceNumber[i] != 0 {
+			break
+		}
+		if i == 0 {
+			return nil, errors.New("hpke: message limit reached")
+		}
+	}
+	return nonce, nil
+}
+
+type sealCtx struct{ *encdecCtx }
+
+func (s *sealCtx) Seal(pt, aad []byte) ([]byte, error) {
+	if s.aead == nil {
+		return nil, ErrExportOnly
+	}
+	nonce, err := s.nextNonce()
+	if err != nil {
+		return nil, err
+	}
+	return s.aead.Seal(nil, nonce, pt, aad), nil
+}
+
+type openCtx struct{ *encdecCtx }
+
+func (o *openCtx) Open(ct, aad []byte) ([]byte, error) {
+	if o.aead == nil {
+		return nil, ErrExportOnly
+	}
+	nonce, err := o.nextNonce()
+	if err != nil {
+		return nil, err
+	}
+	if len(ct) < o.aead.Overhead() {
+		return nil, errors.New("hpke: ciphertext too short")
+	}
+	return o.aead.Open(nil, nonce, ct, aad)
+}
@@ -0,0 +1,12 @@
+package hpke
+
+// extractAndExpand implements the DHKEM ExtractAndExpand operation of
+// RFC 9180 §4.1, turning a Diffie-Hellman output into a KEM shared secret.
+func extractAndExpand(kemID KemID, dh, kemContext []byte) []byte {
+	suiteID := kemID.suiteID()
+	kdf := kemID.kdf()
+	eaePrk := labeledExtract(kdf, suiteID, nil, "eae_prk", dh)
+	return labeledExpand(
+		kdf, suiteID, eaePrk, "shared_secret", kemContext, kdf.ExtractSize(),
+	)
+}
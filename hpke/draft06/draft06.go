@@ -0,0 +1,319 @@
+// Package draft06 implements the pre-standard HPKE wire format of
+// draft-irtf-cfrg-hpke-06, for interop with peers that have not yet moved
+// to the final RFC 9180 encoding (differing only in the "HPKE-06" version
+// label and the resulting key-schedule constants).
+//
+// New code should use the parent hpke package instead; this package exists
+// solely to keep existing draft-06 deployments working. Draft-06 predates
+// the P-384 and P-521 DHKEMs, so Suite.KemID is restricted to P-256,
+// X25519, and X448.
+package draft06
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/cloudflare/circl/hpke"
+	"github.com/cloudflare/circl/kem"
+)
+
+const versionLabel = "HPKE-06"
+
+// modeID represents an HPKE variant.
+type modeID = uint8
+
+const (
+	// modeBase provides hybrid public-key encryption.
+	modeBase modeID = 0x00
+	// modePSK provides hybrid public-key encryption with authentication
+	// using a pre-shared key.
+	modePSK modeID = 0x01
+	// modeAuth provides hybrid public-key encryption with authentication
+	// using the sender's secret key.
+	modeAuth modeID = 0x02
+	// modeAuthPSK provides hybrid public-key encryption with authentication
+	// using both a pre-shared key and an asymmetric key.
+	modeAuthPSK modeID = 0x03
+)
+
+// Sealer encrypts a plaintext using an AEAD encryption. The caller supplies
+// the plaintext and associated data; the nonce is stored internally and
+// incremented after each call.
+type Sealer interface {
+	Seal(pt, aad []byte) (ct []byte, err error)
+	Export(expCtx []byte, length uint16) []byte
+}
+
+// Opener decrypts a ciphertext using an AEAD encryption. The caller supplies
+// the ciphertext and associated data; the nonce is stored internally and
+// incremented after each call.
+type Opener interface {
+	Open(ct, aad []byte) (pt []byte, err error)
+	Export(expCtx []byte, length uint16) []byte
+}
+
+type state struct {
+	suite  Suite
+	modeID modeID
+	skS    kem.PrivateKey
+	pkS    kem.PublicKey
+	psk    []byte
+	pskID  []byte
+	info   []byte
+}
+
+// Sender performs hybrid public-key encryption.
+type Sender struct {
+	state
+	pkR kem.PublicKey
+}
+
+// NewSender creates a Sender with knowledge of the receiver's public-key.
+func (suite Suite) NewSender(pkR kem.PublicKey, info []byte) *Sender {
+	return &Sender{state{suite: suite, info: info}, pkR}
+}
+
+// Setup generates a new context used for Base Mode encryption. Returns the
+// Sealer and corresponding encapsulated key.
+func (s *Sender) Setup() (enc []byte, seal Sealer, err error) {
+	return s.buildBase().allSetup()
+}
+
+func (s *Sender) buildBase() *Sender {
+	s.modeID = modeBase
+	return s
+}
+
+// SetupAuth generates a new context used for Auth Mode encryption. Returns
+// the Sealer and corresponding encapsulated key.
+func (s *Sender) SetupAuth(skS kem.PrivateKey) (enc []byte, seal Sealer, err error) {
+	return s.buildAuth(skS).allSetup()
+}
+
+func (s *Sender) buildAuth(skS kem.PrivateKey) *Sender {
+	s.modeID = modeAuth
+	s.state.skS = skS
+	return s
+}
+
+// SetupPSK generates a new context used for PSK Mode encryption. Returns the
+// Sealer and corresponding encapsulated key.
+func (s *Sender) SetupPSK(psk, pskID []byte) (enc []byte, seal Sealer, err error) {
+	return s.buildPSK(psk, pskID).allSetup()
+}
+
+func (s *Sender) buildPSK(psk, pskID []byte) *Sender {
+	s.modeID = modePSK
+	s.state.psk = psk
+	s.state.pskID = pskID
+	return s
+}
+
+// SetupAuthPSK generates a new context used for Auth-PSK Mode encryption.
+// Returns the Sealer and corresponding encapsulated key.
+func (s *Sender) SetupAuthPSK(skS kem.PrivateKey, psk, pskID []byte) (
+	enc []byte, seal Sealer, err error,
+) {
+	return s.buildAuthPSK(skS, psk, pskID).allSetup()
+}
+
+func (s *Sender) buildAuthPSK(skS kem.PrivateKey, psk, pskID []byte) *Sender {
+	s.modeID = modeAuthPSK
+	s.state.skS = skS
+	s.state.psk = psk
+	s.state.pskID = pskID
+	return s
+}
+
+func (s *Sender) allSetup() (enc []byte, seal Sealer, err error) {
+	k := s.suite.KemID.Scheme()
+	var ss []byte
+	switch s.modeID {
+	case modeBase, modePSK:
+		enc, ss, err = k.Encapsulate(s.pkR)
+	case modeAuth, modeAuthPSK:
+		enc, ss, err = k.AuthEncapsulate(s.pkR, s.skS)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx, err := s.suite.keySchedule(s.modeID, ss, s.info, s.psk, s.pskID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return enc, ctx, nil
+}
+
+// Receiver performs hybrid public-key decryption.
+type Receiver struct {
+	state
+	skR kem.PrivateKey
+	enc []byte
+}
+
+// NewReceiver creates a Receiver with knowledge of a private-key.
+func (suite Suite) NewReceiver(skR kem.PrivateKey, info []byte) *Receiver {
+	return &Receiver{state{suite: suite, info: info}, skR, nil}
+}
+
+// Setup generates a new context used for Base Mode encryption. Setup takes
+// an encapsulated key and returns an Opener.
+func (r *Receiver) Setup(enc []byte) (Opener, error) {
+	r.modeID = modeBase
+	r.enc = enc
+	return r.allSetup()
+}
+
+// SetupAuth generates a new context used for Auth Mode encryption. SetupAuth
+// takes an encapsulated key and the sender's public key, and returns an
+// Opener.
+func (r *Receiver) SetupAuth(enc []byte, pkS kem.PublicKey) (Opener, error) {
+	r.modeID = modeAuth
+	r.enc = enc
+	r.state.pkS = pkS
+	return r.allSetup()
+}
+
+// SetupPSK generates a new context used for PSK Mode encryption. SetupPSK
+// takes an encapsulated key and a pre-shared key, and returns an Opener.
+func (r *Receiver) SetupPSK(enc, psk, pskID []byte) (Opener, error) {
+	r.modeID = modePSK
+	r.enc = enc
+	r.state.psk = psk
+	r.state.pskID = pskID
+	return r.allSetup()
+}
+
+// SetupAuthPSK generates a new context used for Auth-PSK Mode encryption.
+// SetupAuthPSK takes an encapsulated key, a pre-shared key, and the sender's
+// public key, and returns an Opener.
+func (r *Receiver) SetupAuthPSK(enc, psk, pskID []byte, pkS kem.PublicKey) (Opener, error) {
+	r.modeID = modeAuthPSK
+	r.enc = enc
+	r.state.psk = psk
+	r.state.pskID = pskID
+	r.state.pkS = pkS
+	return r.allSetup()
+}
+
+func (r *Receiver) allSetup() (Opener, error) {
+	k := r.suite.KemID.Scheme()
+	var ss []byte
+	var err error
+	switch r.modeID {
+	case modeBase, modePSK:
+		ss, err = k.Decapsulate(r.skR, r.enc)
+	case modeAuth, modeAuthPSK:
+		ss, err = k.AuthDecapsulate(r.skR, r.enc, r.pkS)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r.suite.keySchedule(r.modeID, ss, r.info, r.psk, r.pskID)
+}
+
+// Suite is an HPKE cipher suite consisting of a KEM, KDF, and AEAD
+// algorithm, identified using the same registries as the hpke package.
+type Suite struct {
+	KemID  hpke.KemID
+	KdfID  hpke.KdfID
+	AeadID hpke.AeadID
+}
+
+func (suite Suite) suiteID() []byte {
+	id := make([]byte, 4, 10)
+	copy(id, "HPKE")
+	id = binary.BigEndian.AppendUint16(id, uint16(suite.KemID))
+	id = binary.BigEndian.AppendUint16(id, uint16(suite.KdfID))
+	id = binary.BigEndian.AppendUint16(id, uint16(suite.AeadID))
+	return id
+}
+
+// isKnownKem reports whether id is one of the DHKEMs registered as of
+// draft-irtf-cfrg-hpke-06, which predates the P-384 and P-521 entries added
+// in the final RFC.
+func isKnownKem(id hpke.KemID) bool {
+	switch id {
+	case hpke.KemP256Hkdf256, hpke.KemX25519Hkdf256, hpke.KemX448Hkdf512:
+		return true
+	default:
+		return false
+	}
+}
+
+func isKnownKdf(id hpke.KdfID) bool {
+	switch id {
+	case hpke.KdfHkdfSha256, hpke.KdfHkdfSha384, hpke.KdfHkdfSha512:
+		return true
+	default:
+		return false
+	}
+}
+
+type context struct {
+	suite          Suite
+	seal           func(pt, aad []byte) ([]byte, error)
+	open           func(ct, aad []byte) ([]byte, error)
+	exporterSecret []byte
+}
+
+func (ctx *context) Export(expCtx []byte, length uint16) []byte {
+	return hpke.LabeledExpand(
+		ctx.suite.KdfID, versionLabel, ctx.suite.suiteID(), ctx.exporterSecret, "sec", expCtx, int(length),
+	)
+}
+
+func (ctx *context) Seal(pt, aad []byte) ([]byte, error) { return ctx.seal(pt, aad) }
+func (ctx *context) Open(ct, aad []byte) ([]byte, error) { return ctx.open(ct, aad) }
+
+func (suite Suite) keySchedule(mode uint8, ss, info, psk, pskID []byte) (*context, error) {
+	if !isKnownKem(suite.KemID) || !isKnownKdf(suite.KdfID) || suite.AeadID.KeySize() == 0 {
+		return nil, errors.New("draft06: invalid suite")
+	}
+
+	suiteID := suite.suiteID()
+	pskIDHash := hpke.LabeledExtract(suite.KdfID, versionLabel, suiteID, nil, "pskID_hash", pskID)
+	infoHash := hpke.LabeledExtract(suite.KdfID, versionLabel, suiteID, nil, "info_hash", info)
+
+	keyScheduleContext := []byte{mode}
+	keyScheduleContext = append(keyScheduleContext, pskIDHash...)
+	keyScheduleContext = append(keyScheduleContext, infoHash...)
+
+	secret := hpke.LabeledExtract(suite.KdfID, versionLabel, suiteID, ss, "secret", psk)
+
+	nh := suite.KdfID.ExtractSize()
+	exporterSecret := hpke.LabeledExpand(suite.KdfID, versionLabel, suiteID, secret, "exp", keyScheduleContext, nh)
+	key := hpke.LabeledExpand(suite.KdfID, versionLabel, suiteID, secret, "key", keyScheduleContext, suite.AeadID.KeySize())
+	baseNonce := hpke.LabeledExpand(suite.KdfID, versionLabel, suiteID, secret, "base_nonce", keyScheduleContext, hpke.NonceSize)
+
+	aead, err := suite.AeadID.New(key)
+	if err != nil {
+		return nil, err
+	}
+	seq := make([]byte, len(baseNonce))
+
+	nextNonce := func() []byte {
+		nonce := make([]byte, len(baseNonce))
+		for i := range nonce {
+			nonce[i] = baseNonce[i] ^ seq[i]
+		}
+		for i := len(seq) - 1; i >= 0; i-- {
+			seq[i]++
+			if seq[i] != 0 {
+				break
+			}
+		}
+		return nonce
+	}
+
+	return &context{
+		suite:          suite,
+		exporterSecret: exporterSecret,
+		seal: func(pt, aad []byte) ([]byte, error) {
+			return aead.Seal(nil, nextNonce(), pt, aad), nil
+		},
+		open: func(ct, aad []byte) ([]byte, error) {
+			return aead.Open(nil, nextNonce(), ct, aad)
+		},
+	}, nil
+}
@@ -0,0 +1,96 @@
+package draft06
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/cloudflare/circl/hpke"
+	"github.com/cloudflare/circl/internal/test"
+)
+
+func TestRoundTrip(t *testing.T) {
+	kems := []hpke.KemID{hpke.KemP256Hkdf256, hpke.KemX25519Hkdf256, hpke.KemX448Hkdf512}
+	kdfs := []hpke.KdfID{hpke.KdfHkdfSha256, hpke.KdfHkdfSha384, hpke.KdfHkdfSha512}
+	aeads := []hpke.AeadID{hpke.AeadAes128Gcm, hpke.AeadAes256Gcm, hpke.AeadChaCha20Poly1305}
+
+	for _, kemID := range kems {
+		for _, kdfID := range kdfs {
+			for _, aeadID := range aeads {
+				suite := Suite{kemID, kdfID, aeadID}
+				name := fmt.Sprintf("kem=%#x/kdf=%#x/aead=%#x", suite.KemID, suite.KdfID, suite.AeadID)
+				t.Run(name, suite.testModes)
+			}
+		}
+	}
+}
+
+func (suite Suite) testModes(t *testing.T) {
+	k := suite.KemID.Scheme()
+	pkR, skR, err := k.GenerateKeyPair()
+	test.CheckNoErr(t, err, "GenerateKeyPair receiver")
+	pkS, skS, err := k.GenerateKeyPair()
+	test.CheckNoErr(t, err, "GenerateKeyPair sender")
+
+	info := []byte("info")
+	psk, pskID := []byte("a pre-shared key"), []byte("psk-id")
+	pt, aad := []byte("plaintext"), []byte("aad")
+
+	modes := []struct {
+		name string
+		seal func() (enc []byte, seal Sealer, err error)
+		open func(enc []byte) (Opener, error)
+	}{
+		{
+			"Base",
+			func() ([]byte, Sealer, error) { return suite.NewSender(pkR, info).Setup() },
+			func(enc []byte) (Opener, error) { return suite.NewReceiver(skR, info).Setup(enc) },
+		},
+		{
+			"PSK",
+			func() ([]byte, Sealer, error) { return suite.NewSender(pkR, info).SetupPSK(psk, pskID) },
+			func(enc []byte) (Opener, error) { return suite.NewReceiver(skR, info).SetupPSK(enc, psk, pskID) },
+		},
+		{
+			"Auth",
+			func() ([]byte, Sealer, error) { return suite.NewSender(pkR, info).SetupAuth(skS) },
+			func(enc []byte) (Opener, error) { return suite.NewReceiver(skR, info).SetupAuth(enc, pkS) },
+		},
+		{
+			"AuthPSK",
+			func() ([]byte, Sealer, error) {
+				return suite.NewSender(pkR, info).SetupAuthPSK(skS, psk, pskID)
+			},
+			func(enc []byte) (Opener, error) {
+				return suite.NewReceiver(skR, info).SetupAuthPSK(enc, psk, pskID, pkS)
+			},
+		},
+	}
+
+	for _, m := range modes {
+		t.Run(m.name, func(t *testing.T) {
+			enc, sealer, err := m.seal()
+			test.CheckNoErr(t, err, "setup sender")
+
+			opener, err := m.open(enc)
+			test.CheckNoErr(t, err, "setup receiver")
+
+			ct, err := sealer.Seal(pt, aad)
+			test.CheckNoErr(t, err, "seal")
+
+			got, err := opener.Open(ct, aad)
+			test.CheckNoErr(t, err, "open")
+
+			if !bytes.Equal(got, pt) {
+				test.ReportError(t, got, pt)
+			}
+
+			expCtx := []byte("export test")
+			if !bytes.Equal(
+				sealer.Export(expCtx, 32), opener.Export(expCtx, 32),
+			) {
+				t.Error("sender and receiver exported different secrets")
+			}
+		})
+	}
+}
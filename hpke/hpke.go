@@ -1,12 +1,14 @@
-// Package hpke implements the Hybrid Public Key Encryption (HPKE) as specified
-// by draft-irtf-cfrg-hpke-06.
+// Package hpke implements the Hybrid Public Key Encryption (HPKE) as
+// specified by RFC 9180.
 //
 // HPKE works for any combination of an asymmetric-key encapsulation mechanism
 // (KEM), a key derivation function (KDF), and an authenticated symmetric-key
 // encryption scheme with additional data (AEAD).
 //
-// Specification in
-// https://www.ietf.org/archive/id/draft-irtf-cfrg-hpke-06.html
+// Specification in https://www.rfc-editor.org/rfc/rfc9180.html
+//
+// For interop with peers that still speak the pre-standard
+// draft-irtf-cfrg-hpke-06 wire format, see the hpke/draft06 subpackage.
 package hpke
 
 import (
@@ -15,7 +17,7 @@ import (
 	"github.com/cloudflare/circl/kem"
 )
 
-const versionLabel = "HPKE-06"
+const versionLabel = "HPKE-v1"
 
 // Exporter allows exporting secrets from an HPKE context using a
 // variable-length PRF. Export takes as input a context string expCtx and a
@@ -103,6 +105,13 @@ func (s *Sender) buildBase() *Sender {
 	return s
 }
 
+// SetupExportOnly generates a new HPKE context used only for exporting
+// secrets, for a Suite whose AeadID is AeadExportOnly. Returns the Exporter
+// and corresponding encapsulated key.
+func (s *Sender) SetupExportOnly() (enc []byte, exp Exporter, err error) {
+	return s.buildBase().allSetup(s.KemID.Scheme())
+}
+
 // SetupAuth generates a new HPKE context used for Auth Mode encryption.
 // Returns the Sealer and corresponding encapsulated key.
 func (s *Sender) SetupAuth(skS kem.PrivateKey) (
@@ -151,7 +160,7 @@ func (s *Sender) buildAuthPSK(skS kem.PrivateKey, psk, pskID []byte) *Sender {
 // Receiver performs hybrid public-key decryption.
 type Receiver struct {
 	state
-	skR kem.PrivateKey
+	d   kem.Decapsulator
 	enc []byte
 }
 
@@ -159,11 +168,21 @@ type Receiver struct {
 func (suite Suite) NewReceiver(
 	skR kem.PrivateKey,
 	info []byte,
+) (*Receiver, error) {
+	return suite.NewReceiverWithDecapsulator(kem.WrapPrivateKeyDecapsulator(skR), info)
+}
+
+// NewReceiverWithDecapsulator creates a Receiver backed by an arbitrary
+// Decapsulator, so that the private key can be kept outside of Go memory,
+// e.g. in a PKCS#11 token, a TPM2 device, or a remote signer.
+func (suite Suite) NewReceiverWithDecapsulator(
+	d kem.Decapsulator,
+	info []byte,
 ) (*Receiver, error) {
 	if !suite.isValid() {
 		return nil, errors.New("invalid suite")
 	}
-	return &Receiver{state: state{Suite: suite, info: info}, skR: skR}, nil
+	return &Receiver{state: state{Suite: suite, info: info}, d: d}, nil
 }
 
 // Setup generates a new HPKE context used for Base Mode encryption.
@@ -174,6 +193,15 @@ func (r *Receiver) Setup(enc []byte) (Opener, error) {
 	return r.allSetup()
 }
 
+// SetupExportOnly generates a new HPKE context used only for exporting
+// secrets, for a Suite whose AeadID is AeadExportOnly. SetupExportOnly takes
+// an encapsulated key and returns an Exporter.
+func (r *Receiver) SetupExportOnly(enc []byte) (Exporter, error) {
+	r.modeID = modeBase
+	r.enc = enc
+	return r.allSetup()
+}
+
 // SetupAuth generates a new HPKE context used for Auth Mode encryption.
 // SetupAuth takes an encapsulated key and a public key, and returns an opener.
 func (r *Receiver) SetupAuth(enc []byte, pkS kem.PublicKey) (Opener, error) {
@@ -234,12 +262,11 @@ func (s *Sender) allSetup(k kem.AuthScheme) ([]byte, Sealer, error) {
 func (r *Receiver) allSetup() (Opener, error) {
 	var err error
 	var ss []byte
-	k := r.KemID.Scheme()
 	switch r.modeID {
 	case modeBase, modePSK:
-		ss, err = k.Decapsulate(r.skR, r.enc)
+		ss, err = r.d.Decapsulate(r.enc)
 	case modeAuth, modeAuthPSK:
-		ss, err = k.AuthDecapsulate(r.skR, r.enc, r.pkS)
+		ss, err = r.d.AuthDecapsulate(r.enc, r.pkS)
 	}
 	if err != nil {
 		return nil, err
@@ -0,0 +1,272 @@
+package hpke
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/cloudflare/circl/kem"
+)
+
+func TestExportOnly(t *testing.T) {
+	suite := Suite{KemX25519Hkdf256, KdfHkdfSha256, AeadExportOnly}
+	k := suite.KemID.Scheme()
+	pkR, skR, err := k.GenerateKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sender, err := suite.NewSender(pkR, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc, exp, err := sender.SetupExportOnly()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	receiver, err := suite.NewReceiver(skR, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recvExp, err := receiver.SetupExportOnly(enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := exp.Export([]byte("ctx"), 32)
+	want := recvExp.Export([]byte("ctx"), 32)
+	if string(got) != string(want) {
+		t.Error("sender and receiver exported different secrets")
+	}
+
+	sealer := exp.(Sealer)
+	if _, err := sealer.Seal([]byte("pt"), nil); !errors.Is(err, ErrExportOnly) {
+		t.Errorf("Seal on export-only context: got %v, want %v", err, ErrExportOnly)
+	}
+
+	opener := recvExp.(Opener)
+	if _, err := opener.Open([]byte("ct"), nil); !errors.Is(err, ErrExportOnly) {
+		t.Errorf("Open on export-only context: got %v, want %v", err, ErrExportOnly)
+	}
+}
+
+func TestOneShot(t *testing.T) {
+	suite := Suite{KemX25519Hkdf256, KdfHkdfSha256, AeadAes128Gcm}
+	k := suite.KemID.Scheme()
+	pkR, skR, err := k.GenerateKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkS, skS, err := k.GenerateKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, aad, pt := []byte("info"), []byte("aad"), []byte("plaintext")
+	psk, pskID := []byte("a pre-shared key"), []byte("psk-id")
+
+	modes := []struct {
+		name string
+		seal func() (enc, ct []byte, err error)
+		open func(enc, ct []byte) (pt []byte, err error)
+	}{
+		{
+			"Base",
+			func() ([]byte, []byte, error) { return suite.Seal(pkR, info, aad, pt) },
+			func(enc, ct []byte) ([]byte, error) { return suite.Open(skR, enc, info, aad, ct) },
+		},
+		{
+			"Auth",
+			func() ([]byte, []byte, error) { return suite.SealAuth(pkR, skS, info, aad, pt) },
+			func(enc, ct []byte) ([]byte, error) { return suite.OpenAuth(skR, pkS, enc, info, aad, ct) },
+		},
+		{
+			"PSK",
+			func() ([]byte, []byte, error) { return suite.SealPSK(pkR, psk, pskID, info, aad, pt) },
+			func(enc, ct []byte) ([]byte, error) { return suite.OpenPSK(skR, psk, pskID, enc, info, aad, ct) },
+		},
+		{
+			"AuthPSK",
+			func() ([]byte, []byte, error) {
+				return suite.SealAuthPSK(pkR, skS, psk, pskID, info, aad, pt)
+			},
+			func(enc, ct []byte) ([]byte, error) {
+				return suite.OpenAuthPSK(skR, pkS, psk, pskID, enc, info, aad, ct)
+			},
+		},
+	}
+
+	for _, m := range modes {
+		t.Run(m.name, func(t *testing.T) {
+			enc, ct, err := m.seal()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := m.open(enc, ct)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, pt) {
+				t.Errorf("got %x, want %x", got, pt)
+			}
+		})
+	}
+}
+
+// marshaledKeyDecapsulator is a kem.Decapsulator that keeps the private key
+// serialized, unmarshaling it only to perform a decapsulation. It stands in
+// for a PKCS#11/TPM2/KMS-backed implementation that never materializes a
+// kem.PrivateKey in Go memory, to prove NewReceiverWithDecapsulator works
+// with backends other than kem.WrapPrivateKeyDecapsulator.
+type marshaledKeyDecapsulator struct {
+	scheme kem.AuthScheme
+	skR    []byte
+}
+
+func (d marshaledKeyDecapsulator) Decapsulate(ct []byte) ([]byte, error) {
+	sk, err := d.scheme.UnmarshalBinaryPrivateKey(d.skR)
+	if err != nil {
+		return nil, err
+	}
+	return d.scheme.Decapsulate(sk, ct)
+}
+
+func (d marshaledKeyDecapsulator) AuthDecapsulate(ct []byte, pkS kem.PublicKey) ([]byte, error) {
+	sk, err := d.scheme.UnmarshalBinaryPrivateKey(d.skR)
+	if err != nil {
+		return nil, err
+	}
+	return d.scheme.AuthDecapsulate(sk, ct, pkS)
+}
+
+// TestP384RoundTrip exercises DHKEM(P-384, HKDF-SHA384) through all four
+// modes. The RFC-9180 CFRG vector file does not cover this KEM (kem_id
+// 0x0011 is absent from testdata/vectors.json), so there is no known-answer
+// coverage for it; this is a hand-written round trip instead.
+func TestP384RoundTrip(t *testing.T) {
+	suite := Suite{KemP384HkdfSha384, KdfHkdfSha384, AeadAes256Gcm}
+	k := suite.KemID.Scheme()
+	pkR, skR, err := k.GenerateKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkS, skS, err := k.GenerateKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, aad, pt := []byte("info"), []byte("aad"), []byte("plaintext")
+	psk, pskID := []byte("a pre-shared key"), []byte("psk-id")
+
+	modes := []struct {
+		name string
+		seal func() (enc, ct []byte, err error)
+		open func(enc, ct []byte) (pt []byte, err error)
+	}{
+		{
+			"Base",
+			func() ([]byte, []byte, error) { return suite.Seal(pkR, info, aad, pt) },
+			func(enc, ct []byte) ([]byte, error) { return suite.Open(skR, enc, info, aad, ct) },
+		},
+		{
+			"Auth",
+			func() ([]byte, []byte, error) { return suite.SealAuth(pkR, skS, info, aad, pt) },
+			func(enc, ct []byte) ([]byte, error) { return suite.OpenAuth(skR, pkS, enc, info, aad, ct) },
+		},
+		{
+			"PSK",
+			func() ([]byte, []byte, error) { return suite.SealPSK(pkR, psk, pskID, info, aad, pt) },
+			func(enc, ct []byte) ([]byte, error) { return suite.OpenPSK(skR, psk, pskID, enc, info, aad, ct) },
+		},
+		{
+			"AuthPSK",
+			func() ([]byte, []byte, error) {
+				return suite.SealAuthPSK(pkR, skS, psk, pskID, info, aad, pt)
+			},
+			func(enc, ct []byte) ([]byte, error) {
+				return suite.OpenAuthPSK(skR, pkS, psk, pskID, enc, info, aad, ct)
+			},
+		},
+	}
+
+	for _, m := range modes {
+		t.Run(m.name, func(t *testing.T) {
+			enc, ct, err := m.seal()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := m.open(enc, ct)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, pt) {
+				t.Errorf("got %x, want %x", got, pt)
+			}
+		})
+	}
+}
+
+func TestNewReceiverWithDecapsulator(t *testing.T) {
+	suite := Suite{KemX25519Hkdf256, KdfHkdfSha256, AeadAes128Gcm}
+	k := suite.KemID.Scheme()
+	pkR, skR, err := k.GenerateKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkS, skS, err := k.GenerateKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	skRBytes, err := skR.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := marshaledKeyDecapsulator{k, skRBytes}
+
+	info, aad, pt := []byte("info"), []byte("aad"), []byte("plaintext")
+
+	t.Run("Base", func(t *testing.T) {
+		enc, ct, err := suite.Seal(pkR, info, aad, pt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		receiver, err := suite.NewReceiverWithDecapsulator(d, info)
+		if err != nil {
+			t.Fatal(err)
+		}
+		opener, err := receiver.Setup(enc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := opener.Open(ct, aad)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, pt) {
+			t.Errorf("got %x, want %x", got, pt)
+		}
+	})
+
+	t.Run("Auth", func(t *testing.T) {
+		enc, ct, err := suite.SealAuth(pkR, skS, info, aad, pt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		receiver, err := suite.NewReceiverWithDecapsulator(d, info)
+		if err != nil {
+			t.Fatal(err)
+		}
+		opener, err := receiver.SetupAuth(enc, pkS)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := opener.Open(ct, aad)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, pt) {
+			t.Errorf("got %x, want %x", got, pt)
+		}
+	})
+}
@@ -0,0 +1,132 @@
+package hpke
+
+import "github.com/cloudflare/circl/kem"
+
+// Seal performs a single-shot Base Mode encryption: it encapsulates to pkR,
+// seals pt under aad, and returns the encapsulated key alongside the
+// ciphertext.
+func (suite Suite) Seal(pkR kem.PublicKey, info, aad, pt []byte) (enc, ct []byte, err error) {
+	sender, err := suite.NewSender(pkR, info)
+	if err != nil {
+		return nil, nil, err
+	}
+	enc, sealer, err := sender.Setup()
+	if err != nil {
+		return nil, nil, err
+	}
+	ct, err = sealer.Seal(pt, aad)
+	if err != nil {
+		return nil, nil, err
+	}
+	return enc, ct, nil
+}
+
+// Open performs a single-shot Base Mode decryption of a message produced by
+// Seal.
+func (suite Suite) Open(skR kem.PrivateKey, enc, info, aad, ct []byte) (pt []byte, err error) {
+	receiver, err := suite.NewReceiver(skR, info)
+	if err != nil {
+		return nil, err
+	}
+	opener, err := receiver.Setup(enc)
+	if err != nil {
+		return nil, err
+	}
+	return opener.Open(ct, aad)
+}
+
+// SealAuth performs a single-shot Auth Mode encryption, authenticating the
+// sender with skS.
+func (suite Suite) SealAuth(pkR kem.PublicKey, skS kem.PrivateKey, info, aad, pt []byte) (enc, ct []byte, err error) {
+	sender, err := suite.NewSender(pkR, info)
+	if err != nil {
+		return nil, nil, err
+	}
+	enc, sealer, err := sender.SetupAuth(skS)
+	if err != nil {
+		return nil, nil, err
+	}
+	ct, err = sealer.Seal(pt, aad)
+	if err != nil {
+		return nil, nil, err
+	}
+	return enc, ct, nil
+}
+
+// OpenAuth performs a single-shot Auth Mode decryption of a message produced
+// by SealAuth, authenticating the sender with pkS.
+func (suite Suite) OpenAuth(skR kem.PrivateKey, pkS kem.PublicKey, enc, info, aad, ct []byte) (pt []byte, err error) {
+	receiver, err := suite.NewReceiver(skR, info)
+	if err != nil {
+		return nil, err
+	}
+	opener, err := receiver.SetupAuth(enc, pkS)
+	if err != nil {
+		return nil, err
+	}
+	return opener.Open(ct, aad)
+}
+
+// SealPSK performs a single-shot PSK Mode encryption, authenticating with
+// the pre-shared key psk identified by pskID.
+func (suite Suite) SealPSK(pkR kem.PublicKey, psk, pskID, info, aad, pt []byte) (enc, ct []byte, err error) {
+	sender, err := suite.NewSender(pkR, info)
+	if err != nil {
+		return nil, nil, err
+	}
+	enc, sealer, err := sender.SetupPSK(psk, pskID)
+	if err != nil {
+		return nil, nil, err
+	}
+	ct, err = sealer.Seal(pt, aad)
+	if err != nil {
+		return nil, nil, err
+	}
+	return enc, ct, nil
+}
+
+// OpenPSK performs a single-shot PSK Mode decryption of a message produced
+// by SealPSK.
+func (suite Suite) OpenPSK(skR kem.PrivateKey, psk, pskID, enc, info, aad, ct []byte) (pt []byte, err error) {
+	receiver, err := suite.NewReceiver(skR, info)
+	if err != nil {
+		return nil, err
+	}
+	opener, err := receiver.SetupPSK(enc, psk, pskID)
+	if err != nil {
+		return nil, err
+	}
+	return opener.Open(ct, aad)
+}
+
+// SealAuthPSK performs a single-shot Auth-PSK Mode encryption, combining
+// sender authentication via skS with a pre-shared key.
+func (suite Suite) SealAuthPSK(pkR kem.PublicKey, skS kem.PrivateKey, psk, pskID, info, aad, pt []byte) (enc, ct []byte, err error) {
+	sender, err := suite.NewSender(pkR, info)
+	if err != nil {
+		return nil, nil, err
+	}
+	enc, sealer, err := sender.SetupAuthPSK(skS, psk, pskID)
+	if err != nil {
+		return nil, nil, err
+	}
+	ct, err = sealer.Seal(pt, aad)
+	if err != nil {
+		return nil, nil, err
+	}
+	return enc, ct, nil
+}
+
+// OpenAuthPSK performs a single-shot Auth-PSK Mode decryption of a message
+// produced by SealAuthPSK.
+func (suite Suite) OpenAuthPSK(skR kem.PrivateKey, pkS kem.PublicKey, psk, pskID, enc, info, aad, ct []byte) (pt []byte, err error) {
+	receiver, err := suite.NewReceiver(skR, info)
+	if err != nil {
+		return nil, err
+	}
+	opener, err := receiver.SetupAuthPSK(enc, psk, pskID, pkS)
+	if err != nil {
+		return nil, err
+	}
+	return opener.Open(ct, aad)
+}
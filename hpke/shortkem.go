@@ -0,0 +1,286 @@
+package hpke
+
+import (
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"io"
+	"math/big"
+
+	"github.com/cloudflare/circl/kem"
+)
+
+var (
+	shortP256 = shortKem{name: "P256", curve: elliptic.P256(), kemID: KemP256Hkdf256}
+	shortP384 = shortKem{name: "P384", curve: elliptic.P384(), kemID: KemP384HkdfSha384}
+	shortP521 = shortKem{name: "P521", curve: elliptic.P521(), kemID: KemP521HkdfSha512}
+)
+
+// shortKem implements DHKEM over a short Weierstrass curve from
+// crypto/elliptic, as used by DHKEM(P-256, HKDF-SHA256) and friends.
+type shortKem struct {
+	name  string
+	curve elliptic.Curve
+	kemID KemID
+}
+
+func (k shortKem) Name() string {
+	return "DHKEM(" + k.name + ", HKDF-" + k.kemID.kdf().hashName() + ")"
+}
+
+func (k shortKem) sizeDh() int { return (k.curve.Params().BitSize + 7) / 8 }
+func (k shortKem) sizePk() int { return 1 + 2*k.sizeDh() }
+
+func (k shortKem) PublicKeySize() int  { return k.sizePk() }
+func (k shortKem) PrivateKeySize() int { return k.sizeDh() }
+func (k shortKem) SeedSize() int       { return k.sizeDh() }
+func (k shortKem) SharedKeySize() int  { return k.kemID.kdf().ExtractSize() }
+func (k shortKem) CiphertextSize() int { return k.sizePk() }
+
+type shortPubKey struct {
+	kemID KemID
+	x, y  *big.Int
+}
+
+func (pk *shortPubKey) Scheme() kem.Scheme { return pk.kemID.Scheme() }
+
+func (pk *shortPubKey) Equal(o kem.PublicKey) bool {
+	other, ok := o.(*shortPubKey)
+	return ok && pk.kemID == other.kemID &&
+		pk.x.Cmp(other.x) == 0 && pk.y.Cmp(other.y) == 0
+}
+
+func (pk *shortPubKey) MarshalBinary() ([]byte, error) {
+	k := pk.kemID.Scheme().(shortKem)
+	return elliptic.Marshal(k.curve, pk.x, pk.y), nil
+}
+
+type shortPrivKey struct {
+	kemID KemID
+	d     []byte
+	pub   *shortPubKey
+}
+
+func (sk *shortPrivKey) Scheme() kem.Scheme { return sk.kemID.Scheme() }
+
+func (sk *shortPrivKey) Equal(o kem.PrivateKey) bool {
+	other, ok := o.(*shortPrivKey)
+	return ok && sk.kemID == other.kemID &&
+		subtle.ConstantTimeCompare(sk.d, other.d) == 1
+}
+
+func (sk *shortPrivKey) Public() kem.PublicKey { return sk.pub }
+
+func (sk *shortPrivKey) MarshalBinary() ([]byte, error) {
+	return append([]byte(nil), sk.d...), nil
+}
+
+func (k shortKem) GenerateKeyPair() (kem.PublicKey, kem.PrivateKey, error) {
+	seed := make([]byte, k.SeedSize())
+	if _, err := io.ReadFull(rand.Reader, seed); err != nil {
+		return nil, nil, err
+	}
+	pk, sk := k.deriveKeyPair(seed)
+	return pk, sk, nil
+}
+
+func (k shortKem) UnmarshalBinaryPublicKey(buf []byte) (kem.PublicKey, error) {
+	x, y := elliptic.Unmarshal(k.curve, buf)
+	if x == nil {
+		return nil, errors.New("hpke: invalid public key")
+	}
+	return &shortPubKey{k.kemID, x, y}, nil
+}
+
+func (k shortKem) UnmarshalBinaryPrivateKey(buf []byte) (kem.PrivateKey, error) {
+	if len(buf) != k.sizeDh() {
+		return nil, errors.New("hpke: invalid private key")
+	}
+	x, y := k.curve.ScalarBaseMult(buf)
+	pub := &shortPubKey{k.kemID, x, y}
+	return &shortPrivKey{k.kemID, append([]byte(nil), buf...), pub}, nil
+}
+
+// dh computes the x-coordinate of sk.d * pk, encoded as a fixed-size
+// big-endian string, per RFC 9180 §4.1.
+func (k shortKem) dh(sk *shortPrivKey, pk *shortPubKey) ([]byte, error) {
+	x, y := k.curve.ScalarMult(pk.x, pk.y, sk.d)
+	if x.Sign() == 0 && y.Sign() == 0 {
+		return nil, errors.New("hpke: DH output is the point at infinity")
+	}
+	out := make([]byte, k.sizeDh())
+	xBytes := x.Bytes()
+	copy(out[len(out)-len(xBytes):], xBytes)
+	return out, nil
+}
+
+// deriveKeyPair implements DeriveKeyPair for NIST curves as defined in
+// RFC 9180 §7.1.3, rejection-sampling candidate scalars out of
+// HKDF-Expand(dkp_prk, "candidate" || I2OSP(counter, 1), Nsk).
+func (k shortKem) deriveKeyPair(ikm []byte) (*shortPubKey, *shortPrivKey) {
+	suiteID := k.kemID.suiteID()
+	kdf := k.kemID.kdf()
+	dkpPrk := labeledExtract(kdf, suiteID, nil, "dkp_prk", ikm)
+
+	order := k.curve.Params().N
+	bitmask := byte(0xFF)
+	if k.curve.Params().BitSize == 521 {
+		bitmask = 0x01
+	}
+
+	sk := make([]byte, k.sizeDh())
+	for counter := 0; counter < 256; counter++ {
+		sk = labeledExpand(
+			kdf, suiteID, dkpPrk, "candidate", []byte{byte(counter)}, k.sizeDh(),
+		)
+		sk[0] &= bitmask
+		d := new(big.Int).SetBytes(sk)
+		if d.Sign() != 0 && d.Cmp(order) < 0 {
+			break
+		}
+	}
+
+	x, y := k.curve.ScalarBaseMult(sk)
+	pub := &shortPubKey{k.kemID, x, y}
+	return pub, &shortPrivKey{k.kemID, sk, pub}
+}
+
+// DeriveKeyPair deterministically derives a key pair from ikm, as defined
+// in RFC 9180 §7.1.3.
+func (k shortKem) DeriveKeyPair(ikm []byte) (kem.PublicKey, kem.PrivateKey) {
+	pk, sk := k.deriveKeyPair(ikm)
+	return pk, sk
+}
+
+func (k shortKem) Encapsulate(pk kem.PublicKey) (ct, ss []byte, err error) {
+	seed := make([]byte, k.SeedSize())
+	if _, err := io.ReadFull(rand.Reader, seed); err != nil {
+		return nil, nil, err
+	}
+	return k.EncapsulateDeterministically(pk, seed)
+}
+
+func (k shortKem) EncapsulateDeterministically(
+	pk kem.PublicKey, seed []byte,
+) (ct, ss []byte, err error) {
+	pkR, ok := pk.(*shortPubKey)
+	if !ok || pkR.kemID != k.kemID {
+		return nil, nil, errors.New("hpke: mismatched public key")
+	}
+	pkE, skE := k.deriveKeyPair(seed)
+
+	dh, err := k.dh(skE, pkR)
+	if err != nil {
+		return nil, nil, err
+	}
+	encPkE, _ := pkE.MarshalBinary()
+	encPkR, _ := pkR.MarshalBinary()
+	kemContext := append(append([]byte(nil), encPkE...), encPkR...)
+
+	return encPkE, extractAndExpand(k.kemID, dh, kemContext), nil
+}
+
+func (k shortKem) Decapsulate(sk kem.PrivateKey, ct []byte) (ss []byte, err error) {
+	skR, ok := sk.(*shortPrivKey)
+	if !ok || skR.kemID != k.kemID {
+		return nil, errors.New("hpke: mismatched private key")
+	}
+	pk, err := k.UnmarshalBinaryPublicKey(ct)
+	if err != nil {
+		return nil, err
+	}
+	pkE := pk.(*shortPubKey)
+
+	dh, err := k.dh(skR, pkE)
+	if err != nil {
+		return nil, err
+	}
+	encPkR, _ := skR.pub.MarshalBinary()
+	kemContext := append(append([]byte(nil), ct...), encPkR...)
+
+	return extractAndExpand(k.kemID, dh, kemContext), nil
+}
+
+func (k shortKem) AuthEncapsulate(
+	pkr kem.PublicKey, sks kem.PrivateKey,
+) (ct, ss []byte, err error) {
+	seed := make([]byte, k.SeedSize())
+	if _, err := io.ReadFull(rand.Reader, seed); err != nil {
+		return nil, nil, err
+	}
+	return k.authEncap(pkr, sks, seed)
+}
+
+// AuthEncapsulateDeterministically performs AuthEncapsulate, deriving all
+// randomness from the given seed instead of crypto/rand.
+func (k shortKem) AuthEncapsulateDeterministically(
+	pkr kem.PublicKey, sks kem.PrivateKey, seed []byte,
+) (ct, ss []byte, err error) {
+	return k.authEncap(pkr, sks, seed)
+}
+
+func (k shortKem) authEncap(
+	pk kem.PublicKey, sk kem.PrivateKey, seed []byte,
+) (ct, ss []byte, err error) {
+	pkR, ok := pk.(*shortPubKey)
+	if !ok || pkR.kemID != k.kemID {
+		return nil, nil, errors.New("hpke: mismatched public key")
+	}
+	skS, ok := sk.(*shortPrivKey)
+	if !ok || skS.kemID != k.kemID {
+		return nil, nil, errors.New("hpke: mismatched private key")
+	}
+	pkE, skE := k.deriveKeyPair(seed)
+
+	dh1, err := k.dh(skE, pkR)
+	if err != nil {
+		return nil, nil, err
+	}
+	dh2, err := k.dh(skS, pkR)
+	if err != nil {
+		return nil, nil, err
+	}
+	dh := append(dh1, dh2...)
+
+	encPkE, _ := pkE.MarshalBinary()
+	encPkR, _ := pkR.MarshalBinary()
+	encPkS, _ := skS.pub.MarshalBinary()
+	kemContext := append(append(append([]byte(nil), encPkE...), encPkR...), encPkS...)
+
+	return encPkE, extractAndExpand(k.kemID, dh, kemContext), nil
+}
+
+func (k shortKem) AuthDecapsulate(
+	skr kem.PrivateKey, ct []byte, pks kem.PublicKey,
+) (ss []byte, err error) {
+	skR, ok := skr.(*shortPrivKey)
+	if !ok || skR.kemID != k.kemID {
+		return nil, errors.New("hpke: mismatched private key")
+	}
+	pkS, ok := pks.(*shortPubKey)
+	if !ok || pkS.kemID != k.kemID {
+		return nil, errors.New("hpke: mismatched public key")
+	}
+	pk, err := k.UnmarshalBinaryPublicKey(ct)
+	if err != nil {
+		return nil, err
+	}
+	pkE := pk.(*shortPubKey)
+
+	dh1, err := k.dh(skR, pkE)
+	if err != nil {
+		return nil, err
+	}
+	dh2, err := k.dh(skR, pkS)
+	if err != nil {
+		return nil, err
+	}
+	dh := append(dh1, dh2...)
+
+	encPkR, _ := skR.pub.MarshalBinary()
+	encPkS, _ := pkS.MarshalBinary()
+	kemContext := append(append(append([]byte(nil), ct...), encPkR...), encPkS...)
+
+	return extractAndExpand(k.kemID, dh, kemContext), nil
+}
@@ -0,0 +1,332 @@
+package hpke
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"hash"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+
+	"github.com/cloudflare/circl/kem"
+)
+
+// KemID is the identifier of a KEM algorithm as registered in RFC 9180.
+type KemID uint16
+
+const (
+	// KemP256Hkdf256 is DHKEM(P-256, HKDF-SHA256).
+	KemP256Hkdf256 KemID = 0x0010
+	// KemP384HkdfSha384 is DHKEM(P-384, HKDF-SHA384).
+	KemP384HkdfSha384 KemID = 0x0011
+	// KemP521HkdfSha512 is DHKEM(P-521, HKDF-SHA512).
+	KemP521HkdfSha512 KemID = 0x0012
+	// KemX25519Hkdf256 is DHKEM(X25519, HKDF-SHA256).
+	KemX25519Hkdf256 KemID = 0x0020
+	// KemX448Hkdf512 is DHKEM(X448, HKDF-SHA512).
+	KemX448Hkdf512 KemID = 0x0021
+)
+
+func (id KemID) isValid() bool {
+	switch id {
+	case KemP256Hkdf256, KemP384HkdfSha384, KemP521HkdfSha512,
+		KemX25519Hkdf256, KemX448Hkdf512:
+		return true
+	default:
+		return false
+	}
+}
+
+// suiteID returns the `suite_id` used by the KEM's internal key schedule
+// (ExtractAndExpand): "KEM" || I2OSP(kem_id, 2).
+func (id KemID) suiteID() []byte {
+	suiteID := make([]byte, 3, 5)
+	copy(suiteID, "KEM")
+	return binary.BigEndian.AppendUint16(suiteID, uint16(id))
+}
+
+// kdf returns the KDF used internally by this KEM to derive its shared
+// secret, as fixed by the KEM's registry entry in RFC 9180 §7.1.
+func (id KemID) kdf() KdfID {
+	switch id {
+	case KemP256Hkdf256, KemX25519Hkdf256:
+		return KdfHkdfSha256
+	case KemP384HkdfSha384:
+		return KdfHkdfSha384
+	case KemP521HkdfSha512, KemX448Hkdf512:
+		return KdfHkdfSha512
+	default:
+		return 0
+	}
+}
+
+// Scheme returns the kem.AuthScheme implementing this KEM identifier.
+func (id KemID) Scheme() kem.AuthScheme {
+	switch id {
+	case KemP256Hkdf256:
+		return shortP256
+	case KemP384HkdfSha384:
+		return shortP384
+	case KemP521HkdfSha512:
+		return shortP521
+	case KemX25519Hkdf256:
+		return x25519Kem
+	case KemX448Hkdf512:
+		return x448Kem
+	default:
+		return nil
+	}
+}
+
+// KdfID is the identifier of a KDF algorithm as registered in RFC 9180.
+type KdfID uint16
+
+const (
+	// KdfHkdfSha256 is HKDF-SHA256.
+	KdfHkdfSha256 KdfID = 0x0001
+	// KdfHkdfSha384 is HKDF-SHA384.
+	KdfHkdfSha384 KdfID = 0x0002
+	// KdfHkdfSha512 is HKDF-SHA512.
+	KdfHkdfSha512 KdfID = 0x0003
+)
+
+func (id KdfID) isValid() bool {
+	switch id {
+	case KdfHkdfSha256, KdfHkdfSha384, KdfHkdfSha512:
+		return true
+	default:
+		return false
+	}
+}
+
+// hashName returns the name of the underlying hash function, as used in
+// e.g. kem.Scheme.Name.
+func (id KdfID) hashName() string {
+	switch id {
+	case KdfHkdfSha256:
+		return "SHA256"
+	case KdfHkdfSha384:
+		return "SHA384"
+	case KdfHkdfSha512:
+		return "SHA512"
+	default:
+		return "unknown"
+	}
+}
+
+func (id KdfID) hash() func() hash.Hash {
+	switch id {
+	case KdfHkdfSha256:
+		return sha256.New
+	case KdfHkdfSha384:
+		return sha512.New384
+	case KdfHkdfSha512:
+		return sha512.New
+	default:
+		return nil
+	}
+}
+
+// ExtractSize returns Nh, the output size of the KDF's Extract function.
+func (id KdfID) ExtractSize() int {
+	return id.hash()().Size()
+}
+
+func (id KdfID) extract(salt, ikm []byte) []byte {
+	return hkdf.Extract(id.hash(), ikm, salt)
+}
+
+func (id KdfID) expand(prk, info []byte, length int) []byte {
+	out := make([]byte, length)
+	r := hkdf.Expand(id.hash(), prk, info)
+	if _, err := r.Read(out); err != nil {
+		panic(err) // unreachable: length is bounded by 255*Nh.
+	}
+	return out
+}
+
+// AeadID is the identifier of an AEAD algorithm as registered in RFC 9180.
+type AeadID uint16
+
+const (
+	// AeadAes128Gcm is AES-128-GCM.
+	AeadAes128Gcm AeadID = 0x0001
+	// AeadAes256Gcm is AES-256-GCM.
+	AeadAes256Gcm AeadID = 0x0002
+	// AeadChaCha20Poly1305 is ChaCha20Poly1305.
+	AeadChaCha20Poly1305 AeadID = 0x0003
+	// AeadExportOnly indicates an AEAD-less HPKE context that can only be
+	// used to export secrets, not to Seal or Open messages.
+	AeadExportOnly AeadID = 0xFFFF
+)
+
+func (id AeadID) isValid() bool {
+	switch id {
+	case AeadAes128Gcm, AeadAes256Gcm, AeadChaCha20Poly1305, AeadExportOnly:
+		return true
+	default:
+		return false
+	}
+}
+
+// KeySize returns Nk, the length in bytes of the AEAD's key.
+func (id AeadID) KeySize() int {
+	switch id {
+	case AeadAes128Gcm:
+		return 16
+	case AeadAes256Gcm:
+		return 32
+	case AeadChaCha20Poly1305:
+		return chacha20poly1305.KeySize
+	default:
+		return 0
+	}
+}
+
+// New builds the cipher.AEAD identified by id, keyed with key.
+func (id AeadID) New(key []byte) (cipher.AEAD, error) {
+	switch id {
+	case AeadAes128Gcm, AeadAes256Gcm:
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		return cipher.NewGCM(block)
+	case AeadChaCha20Poly1305:
+		return chacha20poly1305.New(key)
+	default:
+		return nil, errors.New("hpke: invalid AEAD identifier")
+	}
+}
+
+func (suite Suite) isValid() bool {
+	return suite.KemID.isValid() && suite.KdfID.isValid() && suite.AeadID.isValid()
+}
+
+// suiteID returns the `suite_id` used throughout the RFC 9180 key schedule:
+// "HPKE" || I2OSP(kem_id, 2) || I2OSP(kdf_id, 2) || I2OSP(aead_id, 2).
+func (suite Suite) suiteID() []byte {
+	id := make([]byte, 4, 10)
+	copy(id, "HPKE")
+	id = binary.BigEndian.AppendUint16(id, uint16(suite.KemID))
+	id = binary.BigEndian.AppendUint16(id, uint16(suite.KdfID))
+	id = binary.BigEndian.AppendUint16(id, uint16(suite.AeadID))
+	return id
+}
+
+// labeledExtract implements LabeledExtract as defined in RFC 9180 §4:
+//
+//	def LabeledExtract(salt, label, ikm):
+//	  labeled_ikm = concat("HPKE-v1", suite_id, label, ikm)
+//	  return Extract(salt, labeled_ikm)
+func labeledExtract(kdf KdfID, suiteID, salt []byte, label string, ikm []byte) []byte {
+	return LabeledExtract(kdf, versionLabel, suiteID, salt, label, ikm)
+}
+
+// labeledExpand implements LabeledExpand as defined in RFC 9180 §4:
+//
+//	def LabeledExpand(prk, label, info, L):
+//	  labeled_info = concat(I2OSP(L, 2), "HPKE-v1", suite_id, label, info)
+//	  return Expand(prk, labeled_info, L)
+func labeledExpand(kdf KdfID, suiteID, prk []byte, label string, info []byte, length int) []byte {
+	return LabeledExpand(kdf, versionLabel, suiteID, prk, label, info, length)
+}
+
+// LabeledExtract implements the LabeledExtract operation of RFC 9180 §4,
+// generalized over the version label so that other HPKE wire formats (such
+// as hpke/draft06, which uses "HPKE-06" instead of "HPKE-v1") can reuse the
+// key-schedule plumbing instead of reimplementing it.
+func LabeledExtract(kdf KdfID, version string, suiteID, salt []byte, label string, ikm []byte) []byte {
+	labeledIkm := append([]byte(version), suiteID...)
+	labeledIkm = append(labeledIkm, label...)
+	labeledIkm = append(labeledIkm, ikm...)
+	return kdf.extract(salt, labeledIkm)
+}
+
+// LabeledExpand implements the LabeledExpand operation of RFC 9180 §4; see
+// LabeledExtract.
+func LabeledExpand(kdf KdfID, version string, suiteID, prk []byte, label string, info []byte, length int) []byte {
+	labeledInfo := binary.BigEndian.AppendUint16(nil, uint16(length))
+	labeledInfo = append(labeledInfo, version...)
+	labeledInfo = append(labeledInfo, suiteID...)
+	labeledInfo = append(labeledInfo, label...)
+	labeledInfo = append(labeledInfo, info...)
+	return kdf.expand(prk, labeledInfo, length)
+}
+
+// keySchedule implements KeySchedule as defined in RFC 9180 §5.1 and returns
+// the resulting encryption context.
+func (s state) keySchedule(ss, info, psk, pskID []byte) (*encdecCtx, error) {
+	if err := s.verifyPSKInputs(psk, pskID); err != nil {
+		return nil, err
+	}
+
+	suiteID := s.Suite.suiteID()
+	pskIDHash := labeledExtract(s.KdfID, suiteID, nil, "psk_id_hash", pskID)
+	infoHash := labeledExtract(s.KdfID, suiteID, nil, "info_hash", info)
+
+	keyScheduleContext := []byte{s.modeID}
+	keyScheduleContext = append(keyScheduleContext, pskIDHash...)
+	keyScheduleContext = append(keyScheduleContext, infoHash...)
+
+	secret := labeledExtract(s.KdfID, suiteID, ss, "secret", psk)
+
+	exporterSecret := labeledExpand(
+		s.KdfID, suiteID, secret, "exp", keyScheduleContext, s.KdfID.ExtractSize(),
+	)
+
+	ctx := &encdecCtx{
+		suite:          s.Suite,
+		exporterSecret: exporterSecret,
+	}
+
+	// AeadExportOnly derives no key or base_nonce: the resulting context
+	// supports Export but not Seal/Open.
+	if s.AeadID == AeadExportOnly {
+		return ctx, nil
+	}
+
+	key := labeledExpand(
+		s.KdfID, suiteID, secret, "key", keyScheduleContext, s.AeadID.KeySize(),
+	)
+	baseNonce := labeledExpand(
+		s.KdfID, suiteID, secret, "base_nonce", keyScheduleContext, NonceSize,
+	)
+
+	aead, err := s.AeadID.New(key)
+	if err != nil {
+		return nil, err
+	}
+	ctx.aead = aead
+	ctx.baseNonce = baseNonce
+	ctx.sequenceNumber = make([]byte, len(baseNonce))
+
+	return ctx, nil
+}
+
+func (s state) verifyPSKInputs(psk, pskID []byte) error {
+	gotPSK := len(psk) != 0
+	gotPSKID := len(pskID) != 0
+	if gotPSK != gotPSKID {
+		return errors.New("hpke: inconsistent PSK inputs")
+	}
+	switch s.modeID {
+	case modeBase, modeAuth:
+		if gotPSK {
+			return errors.New("hpke: PSK input provided when not needed")
+		}
+	case modePSK, modeAuthPSK:
+		if !gotPSK {
+			return errors.New("hpke: missing required PSK input")
+		}
+	}
+	return nil
+}
+
+// NonceSize is Nn, the length in bytes of the AEAD nonce, which RFC 9180
+// fixes at 12 for every AEAD this package supports.
+const NonceSize = 12
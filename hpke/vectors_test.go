@@ -14,9 +14,9 @@ import (
 )
 
 func TestVectors(t *testing.T) {
-	// Test vectors from
-	// https://github.com/cfrg/draft-irtf-cfrg-hpke/blob/draft-irtf-cfrg-hpke-06/test-vectors.json
-	vectors := readFile(t, "testdata/vectors_v06.json")
+	// Test vectors from the RFC 9180 CFRG JSON, at
+	// https://github.com/cfrg/draft-irtf-cfrg-hpke/blob/master/test-vectors.json
+	vectors := readFile(t, "testdata/vectors.json")
 	for i, v := range vectors {
 		t.Run(fmt.Sprintf("v%v", i), v.verify)
 	}
@@ -26,8 +26,11 @@ func (v *vector) verify(t *testing.T) {
 	m := v.ModeID
 	s := Suite{KemID(v.KemID), KdfID(v.KdfID), AeadID(v.AeadID)}
 
+	dhkemScheme := s.KemID.Scheme()
+	v.checkDeriveKeyPair(t, dhkemScheme, m, s)
+
 	seed := hexB(v.IkmE)
-	dhkem := s.KemID.Scheme()
+	dhkem := dhkemScheme.(kem.DeterministicAuthScheme)
 	seededKem := seededKem{seed, dhkem}
 	sender, recv := v.getActors(t, seededKem, s)
 	sealer, opener := v.setup(t, seededKem, sender, recv, m, s)
@@ -39,6 +42,29 @@ func (v *vector) verify(t *testing.T) {
 	v.checkExports(t, opener, m, s)
 }
 
+// checkDeriveKeyPair confirms that DeriveKeyPair(ikmR) reproduces the
+// vector's skRm/pkRm, as RFC 9180 §7.1.3 requires.
+func (v *vector) checkDeriveKeyPair(t *testing.T, k kem.Scheme, m modeID, s Suite) {
+	dks, ok := k.(kem.DeterministicScheme)
+	if !ok {
+		return
+	}
+	h := fmt.Sprintf("mode: %v %v\n", m, s)
+
+	wantPk, err := dks.UnmarshalBinaryPublicKey(hexB(v.PkRm))
+	test.CheckNoErr(t, err, h+"bad public key")
+	wantSk, err := dks.UnmarshalBinaryPrivateKey(hexB(v.SkRm))
+	test.CheckNoErr(t, err, h+"bad private key")
+
+	gotPk, gotSk := dks.DeriveKeyPair(hexB(v.IkmR))
+	if !gotPk.Equal(wantPk) {
+		test.ReportError(t, gotPk, wantPk, m, s)
+	}
+	if !gotSk.Equal(wantSk) {
+		test.ReportError(t, gotSk, wantSk, m, s)
+	}
+}
+
 func (v *vector) getActors(
 	t *testing.T,
 	dhkem kem.Scheme,
@@ -64,23 +90,17 @@ func (v *vector) getActors(
 
 type seededKem struct {
 	seed []byte
-	kem.AuthScheme
+	kem.DeterministicAuthScheme
 }
 
 func (a seededKem) Encapsulate(pk kem.PublicKey) (
 	ct []byte, ss []byte, err error) {
-	return a.AuthScheme.EncapsulateDeterministically(pk, a.seed)
+	return a.DeterministicAuthScheme.EncapsulateDeterministically(pk, a.seed)
 }
 
 func (a seededKem) AuthEncapsulate(pkr kem.PublicKey, sks kem.PrivateKey) (
 	ct []byte, ss []byte, err error) {
-	if kb, ok := a.AuthScheme.(shortKem); ok {
-		return kb.authEncap(pkr, sks, a.seed)
-	}
-	if kb, ok := a.AuthScheme.(xkem); ok {
-		return kb.authEncap(pkr, sks, a.seed)
-	}
-	panic("bad kem")
+	return a.DeterministicAuthScheme.AuthEncapsulateDeterministically(pkr, sks, a.seed)
 }
 
 func (v *vector) setup(t *testing.T, k kem.AuthScheme,
@@ -111,8 +131,7 @@ func (v *vector) setup(t *testing.T, k kem.AuthScheme,
 		x = func() ([]byte, Sealer, error) {
 			skS, err := k.UnmarshalBinaryPrivateKey(hexB(v.SkSm))
 			test.CheckNoErr(t, err, h+"bad private key")
-			se, err = se.buildAuth(skS)
-			test.CheckNoErr(t, err, h+"bad private key")
+			se = se.buildAuth(skS)
 			return se.allSetup(k)
 		}
 		y = func(enc []byte) (Opener, error) {
@@ -125,8 +144,7 @@ func (v *vector) setup(t *testing.T, k kem.AuthScheme,
 		x = func() ([]byte, Sealer, error) {
 			skS, err := k.UnmarshalBinaryPrivateKey(hexB(v.SkSm))
 			test.CheckNoErr(t, err, h+"bad private key")
-			se, err = se.buildAuthPSK(skS, psk, pskid)
-			test.CheckNoErr(t, err, h+"bad private key")
+			se = se.buildAuthPSK(skS, psk, pskid)
 			return se.allSetup(k)
 		}
 		y = func(enc []byte) (Opener, error) {
@@ -187,7 +205,7 @@ func (v *vector) checkExports(t *testing.T, exp Exporter, m modeID, s Suite) {
 		ctx := hexB(expv.ExportContext)
 		want := hexB(expv.ExportValue)
 
-		got := exp.Export(ctx, uint(expv.ExportLength))
+		got := exp.Export(ctx, uint16(expv.ExportLength))
 		if !bytes.Equal(got, want) {
 			test.ReportError(t, got, want, m, s, j)
 		}
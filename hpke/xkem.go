@@ -0,0 +1,268 @@
+package hpke
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+
+	"github.com/cloudflare/circl/dh/x25519"
+	"github.com/cloudflare/circl/dh/x448"
+	"github.com/cloudflare/circl/kem"
+)
+
+var (
+	x25519Kem = xkem{
+		name:  "X25519",
+		size:  x25519.Size,
+		kemID: KemX25519Hkdf256,
+		genPub: func(priv []byte) []byte {
+			var sk, pk x25519.Key
+			copy(sk[:], priv)
+			x25519.KeyGen(&pk, &sk)
+			return pk[:]
+		},
+		sharedSecret: func(priv, pub []byte) ([]byte, bool) {
+			var sk, pk, ss x25519.Key
+			copy(sk[:], priv)
+			copy(pk[:], pub)
+			ok := x25519.Shared(&ss, &sk, &pk)
+			return ss[:], ok
+		},
+	}
+	x448Kem = xkem{
+		name:  "X448",
+		size:  x448.Size,
+		kemID: KemX448Hkdf512,
+		genPub: func(priv []byte) []byte {
+			var sk, pk x448.Key
+			copy(sk[:], priv)
+			x448.KeyGen(&pk, &sk)
+			return pk[:]
+		},
+		sharedSecret: func(priv, pub []byte) ([]byte, bool) {
+			var sk, pk, ss x448.Key
+			copy(sk[:], priv)
+			copy(pk[:], pub)
+			ok := x448.Shared(&ss, &sk, &pk)
+			return ss[:], ok
+		},
+	}
+)
+
+// xkem implements DHKEM over a Montgomery curve (X25519 or X448), whose
+// group operations are delegated to a dedicated dh/x* package.
+type xkem struct {
+	name         string
+	size         int
+	kemID        KemID
+	genPub       func(priv []byte) []byte
+	sharedSecret func(priv, pub []byte) ([]byte, bool)
+}
+
+func (k xkem) Name() string {
+	return "DHKEM(" + k.name + ", HKDF-" + k.kemID.kdf().hashName() + ")"
+}
+
+func (k xkem) PublicKeySize() int  { return k.size }
+func (k xkem) PrivateKeySize() int { return k.size }
+func (k xkem) SeedSize() int       { return k.size }
+func (k xkem) SharedKeySize() int  { return k.kemID.kdf().ExtractSize() }
+func (k xkem) CiphertextSize() int { return k.size }
+
+type xkemPubKey struct {
+	kemID KemID
+	key   []byte
+}
+
+func (pk *xkemPubKey) Scheme() kem.Scheme { return pk.kemID.Scheme() }
+
+func (pk *xkemPubKey) Equal(o kem.PublicKey) bool {
+	other, ok := o.(*xkemPubKey)
+	return ok && pk.kemID == other.kemID && bytes.Equal(pk.key, other.key)
+}
+
+func (pk *xkemPubKey) MarshalBinary() ([]byte, error) {
+	return append([]byte(nil), pk.key...), nil
+}
+
+type xkemPrivKey struct {
+	kemID KemID
+	key   []byte
+	pub   *xkemPubKey
+}
+
+func (sk *xkemPrivKey) Scheme() kem.Scheme { return sk.kemID.Scheme() }
+
+func (sk *xkemPrivKey) Equal(o kem.PrivateKey) bool {
+	other, ok := o.(*xkemPrivKey)
+	return ok && sk.kemID == other.kemID && bytes.Equal(sk.key, other.key)
+}
+
+func (sk *xkemPrivKey) Public() kem.PublicKey { return sk.pub }
+
+func (sk *xkemPrivKey) MarshalBinary() ([]byte, error) {
+	return append([]byte(nil), sk.key...), nil
+}
+
+func (k xkem) GenerateKeyPair() (kem.PublicKey, kem.PrivateKey, error) {
+	seed := make([]byte, k.SeedSize())
+	if _, err := io.ReadFull(rand.Reader, seed); err != nil {
+		return nil, nil, err
+	}
+	pk, sk := k.deriveKeyPair(seed)
+	return pk, sk, nil
+}
+
+func (k xkem) UnmarshalBinaryPublicKey(buf []byte) (kem.PublicKey, error) {
+	if len(buf) != k.size {
+		return nil, errors.New("hpke: invalid public key")
+	}
+	return &xkemPubKey{k.kemID, append([]byte(nil), buf...)}, nil
+}
+
+func (k xkem) UnmarshalBinaryPrivateKey(buf []byte) (kem.PrivateKey, error) {
+	if len(buf) != k.size {
+		return nil, errors.New("hpke: invalid private key")
+	}
+	sk := append([]byte(nil), buf...)
+	pub := &xkemPubKey{k.kemID, k.genPub(sk)}
+	return &xkemPrivKey{k.kemID, sk, pub}, nil
+}
+
+// deriveKeyPair implements DeriveKeyPair for Montgomery curves as defined
+// in RFC 9180 §7.1.3: a single HKDF-Expand(dkp_prk, "sk", Nsk), with
+// clamping performed by the underlying dh/x* package.
+func (k xkem) deriveKeyPair(ikm []byte) (*xkemPubKey, *xkemPrivKey) {
+	suiteID := k.kemID.suiteID()
+	kdf := k.kemID.kdf()
+	dkpPrk := labeledExtract(kdf, suiteID, nil, "dkp_prk", ikm)
+	sk := labeledExpand(kdf, suiteID, dkpPrk, "sk", nil, k.size)
+	pub := &xkemPubKey{k.kemID, k.genPub(sk)}
+	return pub, &xkemPrivKey{k.kemID, sk, pub}
+}
+
+// DeriveKeyPair deterministically derives a key pair from ikm, as defined
+// in RFC 9180 §7.1.3.
+func (k xkem) DeriveKeyPair(ikm []byte) (kem.PublicKey, kem.PrivateKey) {
+	pk, sk := k.deriveKeyPair(ikm)
+	return pk, sk
+}
+
+func (k xkem) Encapsulate(pk kem.PublicKey) (ct, ss []byte, err error) {
+	seed := make([]byte, k.SeedSize())
+	if _, err := io.ReadFull(rand.Reader, seed); err != nil {
+		return nil, nil, err
+	}
+	return k.EncapsulateDeterministically(pk, seed)
+}
+
+func (k xkem) EncapsulateDeterministically(
+	pk kem.PublicKey, seed []byte,
+) (ct, ss []byte, err error) {
+	pkR, ok := pk.(*xkemPubKey)
+	if !ok || pkR.kemID != k.kemID {
+		return nil, nil, errors.New("hpke: mismatched public key")
+	}
+	pkE, skE := k.deriveKeyPair(seed)
+
+	dh, ok := k.sharedSecret(skE.key, pkR.key)
+	if !ok {
+		return nil, nil, errors.New("hpke: DH output is the point at infinity")
+	}
+	kemContext := append(append([]byte(nil), pkE.key...), pkR.key...)
+
+	return append([]byte(nil), pkE.key...), extractAndExpand(k.kemID, dh, kemContext), nil
+}
+
+func (k xkem) Decapsulate(sk kem.PrivateKey, ct []byte) (ss []byte, err error) {
+	skR, ok := sk.(*xkemPrivKey)
+	if !ok || skR.kemID != k.kemID {
+		return nil, errors.New("hpke: mismatched private key")
+	}
+	if len(ct) != k.size {
+		return nil, errors.New("hpke: invalid ciphertext")
+	}
+
+	dh, ok := k.sharedSecret(skR.key, ct)
+	if !ok {
+		return nil, errors.New("hpke: DH output is the point at infinity")
+	}
+	kemContext := append(append([]byte(nil), ct...), skR.pub.key...)
+
+	return extractAndExpand(k.kemID, dh, kemContext), nil
+}
+
+func (k xkem) AuthEncapsulate(
+	pkr kem.PublicKey, sks kem.PrivateKey,
+) (ct, ss []byte, err error) {
+	seed := make([]byte, k.SeedSize())
+	if _, err := io.ReadFull(rand.Reader, seed); err != nil {
+		return nil, nil, err
+	}
+	return k.authEncap(pkr, sks, seed)
+}
+
+// AuthEncapsulateDeterministically performs AuthEncapsulate, deriving all
+// randomness from the given seed instead of crypto/rand.
+func (k xkem) AuthEncapsulateDeterministically(
+	pkr kem.PublicKey, sks kem.PrivateKey, seed []byte,
+) (ct, ss []byte, err error) {
+	return k.authEncap(pkr, sks, seed)
+}
+
+func (k xkem) authEncap(
+	pk kem.PublicKey, sk kem.PrivateKey, seed []byte,
+) (ct, ss []byte, err error) {
+	pkR, ok := pk.(*xkemPubKey)
+	if !ok || pkR.kemID != k.kemID {
+		return nil, nil, errors.New("hpke: mismatched public key")
+	}
+	skS, ok := sk.(*xkemPrivKey)
+	if !ok || skS.kemID != k.kemID {
+		return nil, nil, errors.New("hpke: mismatched private key")
+	}
+	pkE, skE := k.deriveKeyPair(seed)
+
+	dh1, ok := k.sharedSecret(skE.key, pkR.key)
+	if !ok {
+		return nil, nil, errors.New("hpke: DH output is the point at infinity")
+	}
+	dh2, ok := k.sharedSecret(skS.key, pkR.key)
+	if !ok {
+		return nil, nil, errors.New("hpke: DH output is the point at infinity")
+	}
+	dh := append(dh1, dh2...)
+	kemContext := append(append(append([]byte(nil), pkE.key...), pkR.key...), skS.pub.key...)
+
+	return append([]byte(nil), pkE.key...), extractAndExpand(k.kemID, dh, kemContext), nil
+}
+
+func (k xkem) AuthDecapsulate(
+	skr kem.PrivateKey, ct []byte, pks kem.PublicKey,
+) (ss []byte, err error) {
+	skR, ok := skr.(*xkemPrivKey)
+	if !ok || skR.kemID != k.kemID {
+		return nil, errors.New("hpke: mismatched private key")
+	}
+	pkS, ok := pks.(*xkemPubKey)
+	if !ok || pkS.kemID != k.kemID {
+		return nil, errors.New("hpke: mismatched public key")
+	}
+	if len(ct) != k.size {
+		return nil, errors.New("hpke: invalid ciphertext")
+	}
+
+	dh1, ok := k.sharedSecret(skR.key, ct)
+	if !ok {
+		return nil, errors.New("hpke: DH output is the point at infinity")
+	}
+	dh2, ok := k.sharedSecret(skR.key, pkS.key)
+	if !ok {
+		return nil, errors.New("hpke: DH output is the point at infinity")
+	}
+	dh := append(dh1, dh2...)
+	kemContext := append(append(append([]byte(nil), ct...), skR.pub.key...), pkS.key...)
+
+	return extractAndExpand(k.kemID, dh, kemContext), nil
+}
@@ -0,0 +1,141 @@
+// Package kem provides the interfaces that any Key Encapsulation Mechanism
+// (KEM) must satisfy in order to be used by the higher-level protocols in
+// this repository (such as hpke).
+package kem
+
+import "errors"
+
+// PublicKey is the public part of a KEM key pair.
+type PublicKey interface {
+	// Scheme returns the KEM scheme for this public key.
+	Scheme() Scheme
+	Equal(PublicKey) bool
+	MarshalBinary() ([]byte, error)
+}
+
+// PrivateKey is the private part of a KEM key pair.
+type PrivateKey interface {
+	// Scheme returns the KEM scheme for this private key.
+	Scheme() Scheme
+	Equal(PrivateKey) bool
+	Public() PublicKey
+	MarshalBinary() ([]byte, error)
+}
+
+// Scheme represents a specific instance of a Key Encapsulation Mechanism.
+type Scheme interface {
+	// Name of the scheme.
+	Name() string
+
+	// PublicKeySize returns the length, in bytes, of public keys produced
+	// by this scheme.
+	PublicKeySize() int
+	// PrivateKeySize returns the length, in bytes, of private keys
+	// produced by this scheme.
+	PrivateKeySize() int
+	// SeedSize returns the length, in bytes, of the seed consumed by
+	// EncapsulateDeterministically.
+	SeedSize() int
+	// SharedKeySize returns the length, in bytes, of the shared secret
+	// produced by Encapsulate/Decapsulate.
+	SharedKeySize() int
+	// CiphertextSize returns the length, in bytes, of the encapsulated
+	// key produced by Encapsulate.
+	CiphertextSize() int
+
+	// GenerateKeyPair creates a new key pair using randomness from
+	// crypto/rand.
+	GenerateKeyPair() (PublicKey, PrivateKey, error)
+
+	// Encapsulate generates a shared secret and the encapsulation of it
+	// for the given public key, using randomness from crypto/rand.
+	Encapsulate(pk PublicKey) (ct, ss []byte, err error)
+	// EncapsulateDeterministically generates a shared secret and the
+	// encapsulation of it for the given public key, deriving all
+	// randomness from the given seed.
+	EncapsulateDeterministically(pk PublicKey, seed []byte) (ct, ss []byte, err error)
+	// Decapsulate recovers the shared secret contained in ct using the
+	// given private key.
+	Decapsulate(sk PrivateKey, ct []byte) (ss []byte, err error)
+
+	// UnmarshalBinaryPublicKey recovers a public key produced by
+	// PublicKey.MarshalBinary.
+	UnmarshalBinaryPublicKey(buf []byte) (PublicKey, error)
+	// UnmarshalBinaryPrivateKey recovers a private key produced by
+	// PrivateKey.MarshalBinary.
+	UnmarshalBinaryPrivateKey(buf []byte) (PrivateKey, error)
+}
+
+// AuthScheme is a Scheme that additionally supports authenticated
+// encapsulation, in which the encapsulating party proves possession of a
+// private key to the decapsulating party.
+type AuthScheme interface {
+	Scheme
+
+	// AuthEncapsulate performs an authenticated encapsulation to the
+	// public key pkr, authenticated with the private key sks.
+	AuthEncapsulate(pkr PublicKey, sks PrivateKey) (ct, ss []byte, err error)
+	// AuthDecapsulate recovers the shared secret contained in ct using
+	// the private key skr, verifying that it was encapsulated by the
+	// holder of pks.
+	AuthDecapsulate(skr PrivateKey, ct []byte, pks PublicKey) (ss []byte, err error)
+}
+
+// DeterministicScheme is a Scheme that additionally supports deriving a key
+// pair from a seed, as required by e.g. RFC 9180 §7.1.3's DeriveKeyPair.
+// Not every Scheme can do this (lattice- and isogeny-based KEMs generally
+// can't), so it is kept separate from Scheme rather than folded in, to
+// avoid breaking schemes that only support GenerateKeyPair.
+type DeterministicScheme interface {
+	Scheme
+
+	// DeriveKeyPair deterministically derives a key pair from ikm, which
+	// must be at least SeedSize bytes long.
+	DeriveKeyPair(ikm []byte) (PublicKey, PrivateKey)
+}
+
+// DeterministicAuthScheme is an AuthScheme that additionally supports
+// deterministic authenticated encapsulation, as used to replay known-answer
+// test vectors without reaching into unexported implementation types.
+type DeterministicAuthScheme interface {
+	AuthScheme
+
+	// AuthEncapsulateDeterministically performs an authenticated
+	// encapsulation to the public key pkr, authenticated with the
+	// private key sks, deriving all randomness from the given seed.
+	AuthEncapsulateDeterministically(pkr PublicKey, sks PrivateKey, seed []byte) (ct, ss []byte, err error)
+}
+
+// Decapsulator performs the decapsulating side of a KEM without requiring
+// the private key to exist in Go memory, so that it can be backed by a
+// PKCS#11 token, a TPM2 device, a cloud KMS, or a remote signer.
+type Decapsulator interface {
+	// Decapsulate recovers the shared secret contained in ct.
+	Decapsulate(ct []byte) (ss []byte, err error)
+	// AuthDecapsulate recovers the shared secret contained in ct,
+	// verifying that it was encapsulated by the holder of pks.
+	AuthDecapsulate(ct []byte, pks PublicKey) (ss []byte, err error)
+}
+
+type privateKeyDecapsulator struct {
+	sk PrivateKey
+}
+
+// WrapPrivateKeyDecapsulator adapts an in-memory PrivateKey into a
+// Decapsulator, for callers that don't need to keep the key out of Go
+// memory.
+func WrapPrivateKeyDecapsulator(sk PrivateKey) Decapsulator {
+	return privateKeyDecapsulator{sk}
+}
+
+func (d privateKeyDecapsulator) Decapsulate(ct []byte) (ss []byte, err error) {
+	return d.sk.Scheme().Decapsulate(d.sk, ct)
+}
+
+func (d privateKeyDecapsulator) AuthDecapsulate(ct []byte, pks PublicKey) (ss []byte, err error) {
+	scheme, ok := d.sk.Scheme().(AuthScheme)
+	if !ok {
+		return nil, errors.New("kem: scheme does not support authenticated encapsulation")
+	}
+	return scheme.AuthDecapsulate(d.sk, ct, pks)
+}